@@ -0,0 +1,100 @@
+// binary.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+)
+
+// binaryProbeSize是检测二进制文件时读取的前缀字节数，
+// 与grep/git判断二进制文件时采用的窗口大小一致
+const binaryProbeSize = 8192
+
+// looksBinary沿用grep/git的经验规则：前binaryProbeSize字节内出现NUL字节即视为二进制
+func looksBinary(b []byte) bool {
+	return bytes.IndexByte(b, 0) != -1
+}
+
+// probeBinary读取file开头的binaryProbeSize字节判断是否为二进制文件，
+// 调用后会把文件指针重置回0
+func probeBinary(file *os.File) (bool, error) {
+	buf := make([]byte, binaryProbeSize)
+	n, err := file.Read(buf)
+	if err != nil && err != io.EOF {
+		if _, serr := file.Seek(0, 0); serr != nil {
+			return false, serr
+		}
+		return false, err
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		return false, err
+	}
+	return looksBinary(buf[:n]), nil
+}
+
+// isTextFile保留原有调用方式，内部改为NUL字节探测实现
+func isTextFile(file *os.File) bool {
+	isBinary, err := probeBinary(file)
+	if err != nil {
+		return false
+	}
+	return !isBinary
+}
+
+// binaryAction描述searchFile/searchFileContext对一个文件应采取的处理方式
+type binaryAction int
+
+const (
+	actionAsText    binaryAction = iota // 按文本文件正常扫描
+	actionSkip                          // 跳过该文件，不产生任何结果
+	actionMatchOnly                     // 只报告"是否匹配"，不输出具体行
+)
+
+// decideBinaryAction根据文件是否为二进制、-text-only以及-binary的取值
+// 决定具体的处理方式，-text-only=true且-binary=skip（默认组合）时
+// 保持与历史版本完全一致的行为。
+// binaryExplicit标记-binary是否由用户在命令行上显式指定：显式指定时
+// -binary的取值始终优先生效，不再被-text-only=false隐式覆盖为按文本处理
+func decideBinaryAction(file *os.File, textOnly bool, binaryMode string, binaryExplicit bool) (binaryAction, error) {
+	isBinary, err := probeBinary(file)
+	if err != nil {
+		return actionSkip, err
+	}
+	if !isBinary {
+		return actionAsText, nil
+	}
+
+	switch binaryMode {
+	case "text":
+		return actionAsText, nil
+	case "match-only":
+		return actionMatchOnly, nil
+	default: // "skip"
+		if textOnly || binaryExplicit {
+			return actionSkip, nil
+		}
+		return actionAsText, nil
+	}
+}
+
+// matchOnlyScan实现-binary=match-only的行为：不逐行输出内容，
+// 只在正则命中时报告一条"Binary file ... matches"记录，与grep对二进制文件的处理一致
+func matchOnlyScan(path string, file *os.File, re *regexp.Regexp) ([]Match, error) {
+	if !matchAnywhere(file, re) {
+		return nil, nil
+	}
+	return []Match{{Path: path, Text: fmt.Sprintf("Binary file %s matches", path), Binary: true}}, nil
+}
+
+// matchAnywhere读取file的全部内容判断re是否命中，用于-binary=match-only
+// 这种只需要知道"是否匹配"而不需要逐行定位的场景
+func matchAnywhere(file *os.File, re *regexp.Regexp) bool {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return false
+	}
+	return re.Match(data)
+}
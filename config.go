@@ -0,0 +1,133 @@
+// config.go
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// configEntry是从配置文件中读取到的一条"key = value"记录，
+// 保留原始顺序以便-print-config按文件中的顺序回显
+type configEntry struct {
+	key   string
+	value string
+}
+
+// loadConfigArgs按照用户配置 -> 项目本地配置 的顺序查找第一个存在的配置文件并解析，
+// 返回等价的"-key=value"命令行参数，供调用方prepend到os.Args前面。
+// 项目本地配置（从当前目录向上查找.wingrep.toml）优先于用户级配置。
+func loadConfigArgs() ([]string, string, error) {
+	path := findProjectConfig()
+	if path == "" {
+		path = userConfigPath()
+	}
+	if path == "" {
+		return nil, "", nil
+	}
+
+	entries, err := parseConfigFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", nil
+		}
+		return nil, path, fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+
+	args := make([]string, 0, len(entries))
+	for _, e := range entries {
+		args = append(args, fmt.Sprintf("-%s=%s", e.key, e.value))
+	}
+	return args, path, nil
+}
+
+// userConfigPath返回 ~/.config/wingrep.toml
+func userConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	path := filepath.Join(home, ".config", "wingrep.toml")
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+// findProjectConfig从当前工作目录开始向上查找.wingrep.toml，直到文件系统根目录
+func findProjectConfig() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	for {
+		candidate := filepath.Join(dir, ".wingrep.toml")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// parseConfigFile解析一个TOML/INI风格的配置文件，只支持实际会用到的子集：
+// "key = value"形式的顶层赋值，"#"/";"开头的注释，以及用于分组但被忽略的[section]标题。
+// 值两侧的引号会被去掉，方便在TOML里写成 include = "*.go" 这种形式。
+func parseConfigFile(path string) ([]configEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []configEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			// 忽略分组标题，所有配置按顶层键处理
+			continue
+		}
+
+		idx := strings.Index(line, "=")
+		if idx < 0 {
+			continue
+		}
+
+		key := strings.TrimSpace(line[:idx])
+		value := strings.TrimSpace(line[idx+1:])
+		value = strings.Trim(value, `"'`)
+		if key == "" {
+			continue
+		}
+
+		entries = append(entries, configEntry{key: key, value: value})
+	}
+
+	return entries, scanner.Err()
+}
+
+// hasRawFlag在flag.Parse之前扫描原始参数，检查是否出现了给定名称的布尔flag，
+// 用于在加载配置文件前尽早识别-no-config
+func hasRawFlag(args []string, name string) bool {
+	for _, a := range args {
+		if a == "--" {
+			break
+		}
+		a = strings.TrimPrefix(a, "--")
+		a = strings.TrimPrefix(a, "-")
+		if a == name || strings.HasPrefix(a, name+"=") {
+			return true
+		}
+	}
+	return false
+}
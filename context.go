@@ -0,0 +1,130 @@
+// context.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+)
+
+// ContextLine 是上下文行模式(-A/-B/-C)下的一行输出，
+// IsMatch标记该行本身是否命中了匹配
+type ContextLine struct {
+	LineNum int
+	Text    string
+	IsMatch bool
+}
+
+// searchFileContext 以grep兼容的方式扫描path，为每个匹配收集前后N行上下文。
+// 使用一个大小为before的环形缓冲区保存最近的若干行，并用afterRemaining
+// 倒计时跟踪待输出的"之后"行数，跨越多次匹配时自动合并重叠的窗口，
+// 保证同一行不会被输出两次。
+func searchFileContext(path string, re *regexp.Regexp, before, after int, textOnly bool, binaryMode string, binaryExplicit bool) ([]ContextLine, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("无法打开文件 %s: %w", path, err)
+	}
+	defer file.Close()
+
+	action, err := decideBinaryAction(file, textOnly, binaryMode, binaryExplicit)
+	if err != nil {
+		return nil, fmt.Errorf("检测文件类型 %s 失败: %w", path, err)
+	}
+	switch action {
+	case actionSkip:
+		return nil, nil
+	case actionMatchOnly:
+		if !matchAnywhere(file, re) {
+			return nil, nil
+		}
+		return []ContextLine{{Text: fmt.Sprintf("Binary file %s matches", path)}}, nil
+	}
+	file.Seek(0, 0)
+
+	reader := bufio.NewReaderSize(file, 1024*1024)
+	lineNum := 0
+	var lineBuffer bytes.Buffer
+
+	var out []ContextLine
+	lastEmitted := 0
+	afterRemaining := 0
+	ring := make([]ContextLine, 0, before)
+
+	pushRing := func(cl ContextLine) {
+		if before <= 0 {
+			return
+		}
+		ring = append(ring, cl)
+		if len(ring) > before {
+			ring = ring[len(ring)-before:]
+		}
+	}
+
+	for {
+		lineNum++
+		line, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			if err != io.EOF {
+				return out, fmt.Errorf("读取文件 %s 错误: %w", path, err)
+			}
+			break
+		}
+
+		lineBuffer.Write(line)
+		if isPrefix {
+			continue
+		}
+		fullLine := lineBuffer.String()
+		lineBuffer.Reset()
+
+		cl := ContextLine{LineNum: lineNum, Text: fullLine}
+
+		if re.MatchString(fullLine) {
+			// 先补齐尚未输出过的"之前"行
+			for _, b := range ring {
+				if b.LineNum > lastEmitted {
+					out = append(out, b)
+					lastEmitted = b.LineNum
+				}
+			}
+			cl.IsMatch = true
+			out = append(out, cl)
+			lastEmitted = lineNum
+			afterRemaining = after
+		} else if afterRemaining > 0 {
+			out = append(out, cl)
+			lastEmitted = lineNum
+			afterRemaining--
+		}
+
+		pushRing(cl)
+	}
+
+	return out, nil
+}
+
+// printContextLine 以grep兼容格式打印一行上下文：匹配行用":"分隔，
+// 普通上下文行用"-"分隔
+func printContextLine(path string, cl ContextLine, showFilename, showLineNum bool) {
+	if cl.LineNum == 0 {
+		// -binary=match-only产生的整文件提示，不附加文件名/行号前缀
+		fmt.Println(cl.Text)
+		return
+	}
+
+	sep := "-"
+	if cl.IsMatch {
+		sep = ":"
+	}
+
+	if showFilename {
+		fmt.Printf("%s%s", path, sep)
+	}
+	if showLineNum {
+		fmt.Printf("%d%s", cl.LineNum, sep)
+	}
+	fmt.Println(cl.Text)
+}
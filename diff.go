@@ -0,0 +1,175 @@
+// diff.go
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffOpKind标记一个diff片段相对旧文本的关系
+type diffOpKind int
+
+const (
+	diffEqual diffOpKind = iota
+	diffDelete
+	diffInsert
+)
+
+type diffOp struct {
+	kind diffOpKind
+	line string
+}
+
+// diffLines用最长公共子序列(LCS)对比oldLines和newLines，得到逐行的编辑脚本。
+// 对-dry-run这种预览场景，文件通常不大，O(n*m)的经典算法足够用
+func diffLines(oldLines, newLines []string) []diffOp {
+	n, m := len(oldLines), len(newLines)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case oldLines[i] == newLines[j]:
+			ops = append(ops, diffOp{kind: diffEqual, line: oldLines[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{kind: diffDelete, line: oldLines[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: diffInsert, line: newLines[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{kind: diffDelete, line: oldLines[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{kind: diffInsert, line: newLines[j]})
+	}
+
+	return ops
+}
+
+type hunkWindow struct {
+	lo, hi int // ops切片里的闭区间[lo, hi]
+}
+
+// diffLineCap是diffLines()愿意处理的单侧最大行数。diffLines用的是O(n*m)
+// 时间和内存的经典LCS算法，对-dry-run这种预览场景通常够用，但一遇到几万行的
+// 生成/日志类文件，平方级的开销会让-replace ... -dry-run卡死甚至把内存耗尽——
+// 超过该行数直接放弃逐行diff，只给出一条提示，而不是没有上限地跑下去
+const diffLineCap = 4000
+
+// unifiedDiff把old/new两段文本渲染成标准的unified diff格式，
+// 上下文行数固定为3，与常见diff/git diff的默认值一致。
+// 超过diffLineCap行的文件不再计算逐行diff，改为返回一条说明性提示，
+// 避免O(n*m)的LCS在大文件上耗尽时间或内存
+func unifiedDiff(path, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+
+	oldLines := splitLinesKeepingCount(oldText)
+	newLines := splitLinesKeepingCount(newText)
+	if len(oldLines) > diffLineCap || len(newLines) > diffLineCap {
+		return fmt.Sprintf("--- a/%s\n+++ b/%s\n内容已变化，但文件超过%d行（%d -> %d行），跳过逐行diff预览以避免卡死；如需确认改动请直接对比文件\n",
+			path, path, diffLineCap, len(oldLines), len(newLines))
+	}
+
+	ops := diffLines(oldLines, newLines)
+	const context = 3
+
+	// oldBefore[i]/newBefore[i]是ops[0:i]中消耗掉的旧/新文件行数，
+	// 用来把ops下标换算成unified diff里的起始行号
+	oldBefore := make([]int, len(ops)+1)
+	newBefore := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldBefore[i+1] = oldBefore[i]
+		newBefore[i+1] = newBefore[i]
+		switch op.kind {
+		case diffEqual:
+			oldBefore[i+1]++
+			newBefore[i+1]++
+		case diffDelete:
+			oldBefore[i+1]++
+		case diffInsert:
+			newBefore[i+1]++
+		}
+	}
+
+	var windows []hunkWindow
+	for idx, op := range ops {
+		if op.kind == diffEqual {
+			continue
+		}
+		lo := idx - context
+		if lo < 0 {
+			lo = 0
+		}
+		hi := idx + context
+		if hi > len(ops)-1 {
+			hi = len(ops) - 1
+		}
+		if n := len(windows); n > 0 && lo <= windows[n-1].hi+1 {
+			if hi > windows[n-1].hi {
+				windows[n-1].hi = hi
+			}
+		} else {
+			windows = append(windows, hunkWindow{lo: lo, hi: hi})
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n", path)
+	fmt.Fprintf(&sb, "+++ b/%s\n", path)
+
+	for _, w := range windows {
+		oldStart := oldBefore[w.lo] + 1
+		newStart := newBefore[w.lo] + 1
+		oldCount := oldBefore[w.hi+1] - oldBefore[w.lo]
+		newCount := newBefore[w.hi+1] - newBefore[w.lo]
+
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, op := range ops[w.lo : w.hi+1] {
+			switch op.kind {
+			case diffEqual:
+				sb.WriteString(" " + op.line + "\n")
+			case diffDelete:
+				sb.WriteString("-" + op.line + "\n")
+			case diffInsert:
+				sb.WriteString("+" + op.line + "\n")
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// splitLinesKeepingCount按"\n"切分文本用于逐行比较，
+// 不保留末尾的空字符串元素，避免因最后是否有换行符导致误判差异
+func splitLinesKeepingCount(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,105 @@
+// emitter.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ColumnOffset 记录匹配起始位置的字节偏移和rune偏移，
+// 二者在包含多字节字符的行中可能不同
+type ColumnOffset struct {
+	Byte int `json:"byte"`
+	Rune int `json:"rune"`
+}
+
+// MatchRecord 是一条匹配的结构化表示，供Emitter输出
+type MatchRecord struct {
+	Path       string       `json:"path"`
+	Line       int          `json:"line"`
+	Column     ColumnOffset `json:"column"`
+	Match      string       `json:"match"`
+	Context    string       `json:"context"`
+	ByteOffset int64        `json:"byte_offset"`
+	Binary     bool         `json:"binary,omitempty"`
+}
+
+// Emitter 负责把MatchRecord渲染成最终输出格式
+type Emitter interface {
+	Emit(rec MatchRecord) error
+	Close() error
+}
+
+// TextEmitter 按照wingrep原有的人类可读格式输出，
+// 即 "path:line:context"（文件名/行号依showFilename/showLineNum可选）
+type TextEmitter struct {
+	w            io.Writer
+	showFilename bool
+	showLineNum  bool
+}
+
+func NewTextEmitter(w io.Writer, showFilename, showLineNum bool) *TextEmitter {
+	return &TextEmitter{w: w, showFilename: showFilename, showLineNum: showLineNum}
+}
+
+func (e *TextEmitter) Emit(rec MatchRecord) error {
+	if rec.Binary {
+		_, err := fmt.Fprintln(e.w, rec.Context)
+		return err
+	}
+
+	if e.showFilename {
+		fmt.Fprintf(e.w, "%s:", rec.Path)
+	}
+	if e.showLineNum {
+		fmt.Fprintf(e.w, "%d:", rec.Line)
+	}
+	_, err := fmt.Fprintln(e.w, rec.Context)
+	return err
+}
+
+func (e *TextEmitter) Close() error {
+	return nil
+}
+
+// JSONEmitter 把所有匹配收集起来，在Close时作为一个JSON数组整体写出
+type JSONEmitter struct {
+	w       io.Writer
+	records []MatchRecord
+}
+
+func NewJSONEmitter(w io.Writer) *JSONEmitter {
+	return &JSONEmitter{w: w}
+}
+
+func (e *JSONEmitter) Emit(rec MatchRecord) error {
+	e.records = append(e.records, rec)
+	return nil
+}
+
+func (e *JSONEmitter) Close() error {
+	enc := json.NewEncoder(e.w)
+	if e.records == nil {
+		e.records = []MatchRecord{}
+	}
+	return enc.Encode(e.records)
+}
+
+// JSONLEmitter 每条匹配独立成一行JSON输出，便于配合jq等工具流式处理
+type JSONLEmitter struct {
+	w   io.Writer
+	enc *json.Encoder
+}
+
+func NewJSONLEmitter(w io.Writer) *JSONLEmitter {
+	return &JSONLEmitter{w: w, enc: json.NewEncoder(w)}
+}
+
+func (e *JSONLEmitter) Emit(rec MatchRecord) error {
+	return e.enc.Encode(rec)
+}
+
+func (e *JSONLEmitter) Close() error {
+	return nil
+}
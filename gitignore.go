@@ -0,0 +1,233 @@
+// gitignore.go
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule 是一条编译好的.gitignore规则
+type ignoreRule struct {
+	baseDir string // 该规则所属.gitignore文件所在目录，用于计算相对路径
+	negate  bool   // 是否为"!"取反规则
+	dirOnly bool   // 规则是否只对目录生效（原始模式以"/"结尾）
+	re      *regexp.Regexp
+}
+
+func (r ignoreRule) matches(path string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	rel, err := filepath.Rel(r.baseDir, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	return r.re.MatchString(rel)
+}
+
+// gitignoreMatcher 实现.gitignore语义的忽略判断，按目录缓存编译好的规则栈，
+// 使得同一目录下的多个文件只需O(1)即可取得其规则栈，整体按深度为O(depth)
+type gitignoreMatcher struct {
+	dirRules  map[string][]ignoreRule // 某目录自身贡献的规则（.gitignore/.git/info/exclude）
+	dirStack  map[string][]ignoreRule // 某目录的累积规则栈（含所有祖先目录）
+	baseRules []ignoreRule            // 全局excludes文件贡献的规则，对所有目录生效
+}
+
+// newGitignoreMatcher 为一次遍历构建匹配器。root通常是用户在命令行上指定的路径，
+// 可能只是仓库的一个子目录，但.gitignore的生效范围是整个仓库，而不是本次调用
+// 恰好传入的那个目录，因此这里需要沿着root向上查找真正的仓库根（含.git的目录），
+// 预加载该根目录下的.git/info/exclude；找不到仓库根时退化为一路climb到文件系统根。
+// stackFor会在取规则栈时继续沿同样的路径向上遍历，确保root之外、仓库根以内的
+// 祖先.gitignore同样生效
+func newGitignoreMatcher(root string) *gitignoreMatcher {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		absRoot = root
+	}
+
+	m := &gitignoreMatcher{
+		dirRules: make(map[string][]ignoreRule),
+		dirStack: make(map[string][]ignoreRule),
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		m.baseRules = append(m.baseRules, loadIgnoreFile(filepath.Join(home, ".config", "git", "ignore"), absRoot)...)
+	}
+	if gitRoot := findGitRoot(absRoot); gitRoot != "" {
+		m.baseRules = append(m.baseRules, loadIgnoreFile(filepath.Join(gitRoot, ".git", "info", "exclude"), gitRoot)...)
+	}
+
+	return m
+}
+
+// findGitRoot从dir开始向上查找包含.git的目录，用作仓库根；
+// 找不到时返回空字符串，调用方应退化为一路climb到文件系统根
+func findGitRoot(dir string) string {
+	for {
+		if info, err := os.Stat(filepath.Join(dir, ".git")); err == nil && info != nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// isIgnored判断path（绝对或相对均可，只要与root处于同一棵树）是否应被忽略
+func (m *gitignoreMatcher) isIgnored(path string, isDir bool) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		absPath = path
+	}
+
+	dir := filepath.Dir(absPath)
+	stack := m.stackFor(dir)
+
+	ignored := false
+	for _, r := range stack {
+		if r.matches(absPath, isDir) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// stackFor返回dir的累积规则栈（自身规则追加在祖先规则之后，保证更具体的规则后匹配、优先级更高）。
+// 会一路沿父目录climb到文件系统根，而不是在命令行传入的路径处停下，
+// 这样仓库根（或更上层）的.gitignore即使在对子目录发起搜索时也能生效
+func (m *gitignoreMatcher) stackFor(dir string) []ignoreRule {
+	if stack, ok := m.dirStack[dir]; ok {
+		return stack
+	}
+
+	var stack []ignoreRule
+	parent := filepath.Dir(dir)
+	if parent != dir {
+		stack = append(stack, m.stackFor(parent)...)
+	} else {
+		stack = append(stack, m.baseRules...)
+	}
+	stack = append(stack, m.rulesForDir(dir)...)
+
+	m.dirStack[dir] = stack
+	return stack
+}
+
+// rulesForDir加载并缓存dir自身的.gitignore规则
+func (m *gitignoreMatcher) rulesForDir(dir string) []ignoreRule {
+	if rules, ok := m.dirRules[dir]; ok {
+		return rules
+	}
+	rules := loadIgnoreFile(filepath.Join(dir, ".gitignore"), dir)
+	m.dirRules[dir] = rules
+	return rules
+}
+
+// loadIgnoreFile读取一个gitignore风格的文件，baseDir是规则相对路径计算的基准目录
+func loadIgnoreFile(path, baseDir string) []ignoreRule {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if rule, ok := compileIgnoreRule(line, baseDir); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// compileIgnoreRule把一行.gitignore模式编译为ignoreRule，支持
+// *、**、?、[abc]、开头的"/"锚定、结尾的"/"表示仅目录，以及"!"取反
+func compileIgnoreRule(line, baseDir string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r\n")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	negate := false
+	if strings.HasPrefix(line, "!") {
+		negate = true
+		line = line[1:]
+	}
+
+	dirOnly := strings.HasSuffix(line, "/")
+	if dirOnly {
+		line = strings.TrimSuffix(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	anchored := strings.HasPrefix(line, "/") || strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	pattern := globToRegexp(line)
+	if anchored {
+		pattern = "^" + pattern + "$"
+	} else {
+		pattern = "^(?:.*/)?" + pattern + "$"
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return ignoreRule{}, false
+	}
+
+	return ignoreRule{baseDir: baseDir, negate: negate, dirOnly: dirOnly, re: re}, true
+}
+
+// globToRegexp把gitignore使用的glob语法子集转换为等价的正则表达式片段
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	runes := []rune(glob)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch c {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				// "**" 匹配任意深度，包含"/"
+				j := i + 2
+				if j < len(runes) && runes[j] == '/' {
+					sb.WriteString("(?:.*/)?")
+					i = j
+				} else {
+					sb.WriteString(".*")
+					i = j - 1
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			// 直接透传字符类到结尾的']'
+			j := i + 1
+			for j < len(runes) && runes[j] != ']' {
+				j++
+			}
+			if j < len(runes) {
+				sb.WriteString(string(runes[i : j+1]))
+				i = j
+			} else {
+				sb.WriteString(regexp.QuoteMeta(string(c)))
+			}
+		case '.', '+', '(', ')', '|', '^', '$', '{', '}', '\\':
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		default:
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
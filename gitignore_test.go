@@ -0,0 +1,57 @@
+// gitignore_test.go
+package main
+
+import "testing"
+
+func TestCompileIgnoreRuleMatches(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"*.log", "a.log", false, true},
+		{"*.log", "sub/a.log", false, true},
+		{"*.log", "a.txt", false, false},
+		{"/build", "build", true, true},
+		{"/build", "sub/build", true, false},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"a?c", "abc", false, true},
+		{"a?c", "ac", false, false},
+		{"[ab].txt", "a.txt", false, true},
+		{"[ab].txt", "c.txt", false, false},
+		{"**/logs", "logs", true, true},
+		{"**/logs", "a/b/logs", true, true},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+	}
+
+	for _, c := range cases {
+		rule, ok := compileIgnoreRule(c.pattern, "/root")
+		if !ok {
+			t.Fatalf("compileIgnoreRule(%q) 编译失败", c.pattern)
+		}
+		got := rule.matches("/root/"+c.path, c.isDir)
+		if got != c.want {
+			t.Errorf("pattern %q path %q isDir=%v: got %v, want %v", c.pattern, c.path, c.isDir, got, c.want)
+		}
+	}
+}
+
+func TestCompileIgnoreRuleNegateAndComments(t *testing.T) {
+	rule, ok := compileIgnoreRule("!important.log", "/root")
+	if !ok {
+		t.Fatal("compileIgnoreRule(\"!important.log\") 编译失败")
+	}
+	if !rule.negate {
+		t.Error("以!开头的规则应该被标记为negate")
+	}
+
+	if _, ok := compileIgnoreRule("# comment", "/root"); ok {
+		t.Error("注释行不应该被编译为规则")
+	}
+	if _, ok := compileIgnoreRule("", "/root"); ok {
+		t.Error("空行不应该被编译为规则")
+	}
+}
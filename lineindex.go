@@ -0,0 +1,61 @@
+// lineindex.go
+package main
+
+import "bytes"
+
+// lineIndexBlockSize是行号索引的分块大小，mmap扫描的大文件按此粒度
+// 懒加载地记录每块起始位置对应的行号
+const lineIndexBlockSize = 1024 * 1024
+
+// lineIndex为mmap扫描到的原始字节建立一个分块的行号索引：每个块只记录
+// 自己起始位置之前出现过多少个换行符，查找某个字节偏移的行号时先用
+// 除法定位所在块（等价于在块边界数组上做二分查找），再在块内统计换行符，
+// 从而避免每次匹配都从文件头重新扫描一遍
+type lineIndex struct {
+	data       []byte
+	blockSize  int
+	blockStart []int // blockStart[i]是第i块起始位置对应的行号（从1开始）
+}
+
+// newLineIndex懒加载地为data建立行号索引，只在mmap路径下按需调用一次
+func newLineIndex(data []byte) *lineIndex {
+	blockSize := lineIndexBlockSize
+	numBlocks := len(data)/blockSize + 1
+
+	li := &lineIndex{
+		data:       data,
+		blockSize:  blockSize,
+		blockStart: make([]int, numBlocks),
+	}
+
+	line := 1
+	pos := 0
+	for b := 0; b < numBlocks; b++ {
+		li.blockStart[b] = line
+		end := pos + blockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		line += bytes.Count(data[pos:end], []byte{'\n'})
+		pos = end
+	}
+
+	return li
+}
+
+// lineAt返回offset所在的行号（从1开始）
+func (li *lineIndex) lineAt(offset int) int {
+	block := offset / li.blockSize
+	if block >= len(li.blockStart) {
+		block = len(li.blockStart) - 1
+	}
+
+	start := block * li.blockSize
+	if start > offset {
+		start = offset
+	}
+
+	line := li.blockStart[block]
+	line += bytes.Count(li.data[start:offset], []byte{'\n'})
+	return line
+}
@@ -0,0 +1,65 @@
+// lineindex_test.go
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func TestLineIndexLineAtWithinSingleBlock(t *testing.T) {
+	data := []byte("line1\nline2\nline3\nline4\n")
+	idx := newLineIndex(data)
+
+	cases := []struct {
+		offset int
+		want   int
+	}{
+		{0, 1},                              // "line1"开头
+		{bytes.IndexByte(data, '\n') + 1, 2}, // "line2"开头
+		{len(data) - 1, 4},                   // 最后一行内
+	}
+	for _, c := range cases {
+		if got := idx.lineAt(c.offset); got != c.want {
+			t.Errorf("lineAt(%d) = %d, want %d", c.offset, got, c.want)
+		}
+	}
+}
+
+// TestLineIndexLineAtAcrossBlocks构造一个跨越多个lineIndexBlockSize分块的
+// 输入，验证按块懒加载的行号索引在块边界附近仍然准确
+func TestLineIndexLineAtAcrossBlocks(t *testing.T) {
+	var buf bytes.Buffer
+	const totalLines = 5000
+	lineOffsets := make([]int, 0, totalLines)
+	for i := 0; i < totalLines; i++ {
+		lineOffsets = append(lineOffsets, buf.Len())
+		buf.WriteString("x")
+		buf.WriteString(strconv.Itoa(i))
+		buf.WriteByte('\n')
+	}
+	data := buf.Bytes()
+
+	// 人为调小块大小，使几千行的输入也能跨越多个块，覆盖块边界的换算逻辑
+	idx := &lineIndex{data: data, blockSize: 64}
+	numBlocks := len(data)/64 + 1
+	idx.blockStart = make([]int, numBlocks)
+	line := 1
+	pos := 0
+	for b := 0; b < numBlocks; b++ {
+		idx.blockStart[b] = line
+		end := pos + 64
+		if end > len(data) {
+			end = len(data)
+		}
+		line += bytes.Count(data[pos:end], []byte{'\n'})
+		pos = end
+	}
+
+	for _, lineNo := range []int{1, 2, 500, 2500, totalLines} {
+		offset := lineOffsets[lineNo-1]
+		if got := idx.lineAt(offset); got != lineNo {
+			t.Errorf("lineAt(%d)（第%d行起始处）= %d, want %d", offset, lineNo, got, lineNo)
+		}
+	}
+}
@@ -4,13 +4,16 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
 	"unicode/utf8"
 )
@@ -26,6 +29,20 @@ func main() {
 	maxChars := flag.Int("max-chars", 200, "每行显示的最大字符数")
 	contextChars := flag.Int("context", 20, "关键词前后保留的上下文字符数")
 	textOnly := flag.Bool("text-only", true, "ture:只处理文本文件，false:处理所有文件")
+	jobs := flag.Int("j", runtime.NumCPU(), "并发worker数量")
+	afterLines := flag.Int("A", 0, "显示匹配行之后的N行（grep兼容的上下文行模式）")
+	beforeLines := flag.Int("B", 0, "显示匹配行之前的N行（grep兼容的上下文行模式）")
+	aroundLines := flag.Int("C", 0, "同时显示匹配行前后N行，等价于 -A N -B N")
+	output := flag.String("output", "text", "输出格式: text|json|jsonl")
+	respectGitignore := flag.Bool("respect-gitignore", false, "遍历时跳过.gitignore/.git/info/exclude/全局excludes忽略的文件")
+	hidden := flag.Bool("hidden", false, "包含隐藏文件和隐藏目录（默认跳过以.开头的文件/目录）")
+	excludeDir := flag.String("exclude-dir", "", "遍历时整体跳过的目录名，逗号分隔（如：node_modules,.git）")
+	noConfig := flag.Bool("no-config", false, "不加载~/.config/wingrep.toml或项目本地.wingrep.toml")
+	printConfig := flag.Bool("print-config", false, "打印从配置文件加载到的参数后退出")
+	binaryMode := flag.String("binary", "skip", "二进制文件处理方式: skip|text|match-only，显式指定时优先于-text-only=false")
+	replace := flag.String("replace", "", "原地替换匹配内容（支持$1、${name}反向引用），与-dry-run/-backup配合使用")
+	dryRun := flag.Bool("dry-run", false, "配合-replace使用，只打印unified diff预览，不修改文件")
+	backup := flag.String("backup", "", "配合-replace使用，替换前把原文件备份为 原文件名+该后缀（如 .bak）")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "用法: %s [选项] 模式 [路径...]\n\n选项:\n", os.Args[0])
@@ -34,8 +51,75 @@ func main() {
 		fmt.Fprintf(os.Stderr, "\n示例:\n  %s 'error' src/\n  %s -n --include=*.go 'func main'\n", os.Args[0], os.Args[0])
 	}
 
+	// 在flag.Parse之前加载配置文件，把配置项转换成"-key=value"形式前置到
+	// 命令行参数里，这样真正的命令行参数总能覆盖配置文件里的值
+	var configPath string
+	if !hasRawFlag(os.Args[1:], "no-config") {
+		cfgArgs, path, err := loadConfigArgs()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		configPath = path
+		if len(cfgArgs) > 0 {
+			os.Args = append([]string{os.Args[0]}, append(cfgArgs, os.Args[1:]...)...)
+		}
+	}
+
 	flag.Parse()
 
+	if *printConfig {
+		if *noConfig || configPath == "" {
+			fmt.Println("未加载配置文件")
+		} else {
+			fmt.Printf("配置文件: %s\n", configPath)
+		}
+		flag.Visit(func(f *flag.Flag) {
+			fmt.Printf("%s = %s\n", f.Name, f.Value.String())
+		})
+		os.Exit(0)
+	}
+
+	// -C同时设置前后行数，除非-A/-B已单独指定
+	before, after := *beforeLines, *afterLines
+	if *aroundLines > 0 {
+		if before == 0 {
+			before = *aroundLines
+		}
+		if after == 0 {
+			after = *aroundLines
+		}
+	}
+	contextMode := before > 0 || after > 0
+
+	if contextMode {
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "context" || f.Name == "max-chars" {
+				log.Fatalf("-%s 与 -A/-B/-C 互斥，请选择其中一种输出模式", f.Name)
+			}
+		})
+	}
+
+	if contextMode && *output != "text" {
+		log.Fatalf("-output=%s 暂不支持 -A/-B/-C 上下文行模式", *output)
+	}
+
+	switch *binaryMode {
+	case "skip", "text", "match-only":
+	default:
+		log.Fatalf("-binary=%s 无效，可选值为 skip|text|match-only", *binaryMode)
+	}
+
+	replaceMode := false
+	binaryExplicit := false
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "replace":
+			replaceMode = true
+		case "binary":
+			binaryExplicit = true
+		}
+	})
+
 	args := flag.Args()
 	if len(args) < 1 {
 		flag.Usage()
@@ -63,17 +147,88 @@ func main() {
 	recursive := !*noRecursive
 
 	// 收集要搜索的文件
-	files := collectFiles(paths, recursive, incFilter, excFilter)
+	walkOpts := walkOptions{
+		respectGitignore: *respectGitignore,
+		hidden:           *hidden,
+		excludeDirs:      splitCommaList(*excludeDir),
+	}
+	files := collectFiles(paths, recursive, incFilter, excFilter, walkOpts)
 	if len(files) == 0 {
 		fmt.Println("未找到匹配的文件")
 		os.Exit(1)
 	}
 
-	// 搜索文件
+	if replaceMode {
+		runReplace(files, re, replaceOptions{
+			replacement: *replace,
+			dryRun:      *dryRun,
+			backupExt:   *backup,
+		})
+		return
+	}
+
+	// 搜索文件，使用有界worker池并发处理，打印协程负责保持输出顺序
 	multiFile := len(files) > 1
 	showFilename := multiFile && !*hideFilename
-	for _, file := range files {
-		searchFile(file, re, showFilename, *showLineNum, *maxChars, *contextChars, *textOnly)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 收到SIGINT时取消ctx，worker池和分发协程据此尽快停止剩余文件的搜索，
+	// 而不是让-j指定的worker们把整批files处理完才退出
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	emitter, err := newEmitter(*output, os.Stdout, showFilename, *showLineNum)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	opts := searchOptions{
+		re:             re,
+		showFilename:   showFilename,
+		showLineNum:    *showLineNum,
+		maxChars:       *maxChars,
+		contextChars:   *contextChars,
+		textOnly:       *textOnly,
+		contextMode:    contextMode,
+		beforeLines:    before,
+		afterLines:     after,
+		emitter:        emitter,
+		binaryMode:     *binaryMode,
+		binaryExplicit: binaryExplicit,
+	}
+	errCount := runSearch(ctx, files, *jobs, opts)
+
+	if err := emitter.Close(); err != nil {
+		log.Fatalf("输出结果失败: %v", err)
+	}
+
+	// 只要有文件读取失败就以非零状态退出，让调用方（脚本/CI）能感知到
+	// 本次搜索并不完整，而不是悄悄吞掉per-file的错误
+	if errCount > 0 {
+		os.Exit(1)
+	}
+}
+
+// newEmitter根据-output的取值构建对应的Emitter实现
+func newEmitter(format string, w io.Writer, showFilename, showLineNum bool) (Emitter, error) {
+	switch format {
+	case "text":
+		return NewTextEmitter(w, showFilename, showLineNum), nil
+	case "json":
+		return NewJSONEmitter(w), nil
+	case "jsonl":
+		return NewJSONLEmitter(w), nil
+	default:
+		return nil, fmt.Errorf("未知的输出格式 %q，可选值为 text|json|jsonl", format)
 	}
 }
 
@@ -105,9 +260,44 @@ func prepareFilter(pattern string) func(string) bool {
 	}
 }
 
-func collectFiles(paths []string, recursive bool, incFilter, excFilter func(string) bool) []string {
+// walkOptions汇总了遍历目录树时需要的额外过滤行为
+type walkOptions struct {
+	respectGitignore bool
+	hidden           bool
+	excludeDirs      []string
+}
+
+// splitCommaList把逗号分隔的字符串切分为非空项的集合
+func splitCommaList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func isHiddenName(name string) bool {
+	return len(name) > 1 && name[0] == '.'
+}
+
+func collectFiles(paths []string, recursive bool, incFilter, excFilter func(string) bool, walkOpts walkOptions) []string {
 	var files []string
 
+	isPruned := func(name string) bool {
+		for _, d := range walkOpts.excludeDirs {
+			if d == name {
+				return true
+			}
+		}
+		return false
+	}
+
 	for _, path := range paths {
 		fileInfo, err := os.Stat(path)
 		if err != nil {
@@ -116,11 +306,31 @@ func collectFiles(paths []string, recursive bool, incFilter, excFilter func(stri
 		}
 
 		if fileInfo.IsDir() {
+			var matcher *gitignoreMatcher
+			if walkOpts.respectGitignore {
+				matcher = newGitignoreMatcher(path)
+			}
+
 			walkFn := func(currentPath string, info os.FileInfo, err error) error {
 				if err != nil {
 					return nil
 				}
+				if currentPath != path && !walkOpts.hidden && isHiddenName(info.Name()) {
+					if info.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
 				if info.IsDir() {
+					if currentPath != path && isPruned(info.Name()) {
+						return filepath.SkipDir
+					}
+					if matcher != nil && matcher.isIgnored(currentPath, true) {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if matcher != nil && matcher.isIgnored(currentPath, false) {
 					return nil
 				}
 				if !includeFile(info.Name(), incFilter, excFilter) {
@@ -143,15 +353,22 @@ func collectFiles(paths []string, recursive bool, incFilter, excFilter func(stri
 					if item.IsDir() {
 						continue
 					}
+					if !walkOpts.hidden && isHiddenName(item.Name()) {
+						continue
+					}
 					fileInfo, err := item.Info()
 					if err != nil {
 						log.Printf("获取文件信息失败: %s: %v", item.Name(), err)
 						continue
 					}
+					fullPath := filepath.Join(path, fileInfo.Name())
+					if matcher != nil && matcher.isIgnored(fullPath, false) {
+						continue
+					}
 					if !includeFile(fileInfo.Name(), incFilter, excFilter) {
 						continue
 					}
-					files = append(files, filepath.Join(path, fileInfo.Name()))
+					files = append(files, fullPath)
 				}
 			}
 		} else {
@@ -174,30 +391,48 @@ func includeFile(name string, incFilter, excFilter func(string) bool) bool {
 	return true
 }
 
-func searchFile(path string, re *regexp.Regexp, showFilename, showLineNum bool, maxChars, contextChars int, textOnly bool) {
+// searchFile 逐行扫描path，返回按行顺序排列的匹配结果。
+// 每个worker独立持有自己的bufio.Reader，互不共享状态，可安全并发调用。
+func searchFile(path string, re *regexp.Regexp, maxChars, contextChars int, textOnly bool, binaryMode string, binaryExplicit bool) ([]Match, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		log.Printf("无法打开文件 %s: %v", path, err)
-		return
+		return nil, fmt.Errorf("无法打开文件 %s: %w", path, err)
 	}
 	defer file.Close()
 
-	// 检查是否为文本文件
-	if textOnly && !isTextFile(file) {
-		return
+	action, err := decideBinaryAction(file, textOnly, binaryMode, binaryExplicit)
+	if err != nil {
+		return nil, fmt.Errorf("检测文件类型 %s 失败: %w", path, err)
+	}
+	switch action {
+	case actionSkip:
+		return nil, nil
+	case actionMatchOnly:
+		return matchOnlyScan(path, file, re)
 	}
 	file.Seek(0, 0) // 重置文件指针
 
+	// 大文件走mmap扫描路径，直接在映射内存上运行正则，避免逐行拷贝
+	if info, err := file.Stat(); err == nil && info.Size() > mmapThreshold {
+		if data, closeMmap, err := openMmap(path); err == nil {
+			defer closeMmap()
+			return searchFileMmap(path, data, re, maxChars, contextChars), nil
+		}
+		// mmap不可用（如平台不支持），回退到bufio路径
+	}
+
 	reader := bufio.NewReaderSize(file, 1024 * 1024) // 1MB缓冲区
 	lineNum := 0
 	var lineBuffer bytes.Buffer
+	var matches []Match
+	var lineStartOffset int64 // 当前行起始位置相对文件开头的字节偏移
 
 	for {
 		lineNum++
 		line, isPrefix, err := reader.ReadLine()
 		if err != nil {
 			if err != io.EOF {
-				log.Printf("读取文件 %s 错误: %v", path, err)
+				return matches, fmt.Errorf("读取文件 %s 错误: %w", path, err)
 			}
 			break
 		}
@@ -207,36 +442,27 @@ func searchFile(path string, re *regexp.Regexp, showFilename, showLineNum bool,
 			fullLine := lineBuffer.String()
 			lineBuffer.Reset()
 
-			matches := re.FindAllStringIndex(fullLine, -1)
-			if matches != nil {
-				for _, match := range matches {
-					start, end := match[0], match[1]
-					trimmedLine := extractMatchContext(fullLine, start, end, maxChars, contextChars)
-					printMatch(path, trimmedLine, lineNum, showFilename, showLineNum)
-				}
+			hits := re.FindAllStringIndex(fullLine, -1)
+			for _, hit := range hits {
+				start, end := hit[0], hit[1]
+				trimmedLine := extractMatchContext(fullLine, start, end, maxChars, contextChars)
+				matches = append(matches, Match{
+					Path:       path,
+					LineNum:    lineNum,
+					Text:       trimmedLine,
+					MatchText:  fullLine[start:end],
+					ByteCol:    start,
+					RuneCol:    utf8.RuneCountInString(fullLine[:start]),
+					ByteOffset: lineStartOffset + int64(start),
+				})
 			}
-		}
-	}
-}
-
-// 检查文件是否为文本文件
-func isTextFile(file *os.File) bool {
-	buffer := make([]byte, 1024)
-	n, err := file.Read(buffer)
-	if err != nil && err != io.EOF {
-		return false
-	}
 
-	// 检查前1024字节中非文本字符的比例
-	nonTextCount := 0
-	for i := 0; i < n; i++ {
-		if buffer[i] == 0 || !utf8.RuneStart(buffer[i]) || buffer[i] < 32 && buffer[i] != '\t' && buffer[i] != '\n' && buffer[i] != '\r' {
-			nonTextCount++
+			// +1 近似换行符长度，与原始实现的行处理方式保持一致
+			lineStartOffset += int64(len(fullLine)) + 1
 		}
 	}
 
-	// 如果超过10%的字符是非文本字符，则认为是二进制文件
-	return float64(nonTextCount)/float64(n) < 0.1
+	return matches, nil
 }
 
 // 从匹配关键词周围提取关键上下文
@@ -312,14 +538,3 @@ func extractMatchContext(line string, start, end, maxChars, contextChars int) st
 	
 	return context
 }
-
-// 精简输出行，突出显示匹配部分
-func printMatch(path, line string, lineNum int, showFilename, showLineNum bool) {
-	if showFilename {
-		fmt.Printf("%s:", path)
-	}
-	if showLineNum {
-		fmt.Printf("%d:", lineNum)
-	}
-	fmt.Println(line)
-}
\ No newline at end of file
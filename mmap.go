@@ -0,0 +1,55 @@
+// mmap.go
+package main
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// mmapThreshold是启用mmap扫描路径的文件大小阈值，超过该大小的文件
+// 直接在映射内存上运行正则，避免bufio.Reader逐行拷贝的开销
+const mmapThreshold = 4 * 1024 * 1024
+
+// openMmap由平台相关的mmap_*.go实现，成功时返回只读映射的字节切片
+// 以及用于释放映射（并关闭底层文件）的closer函数
+
+// searchFileMmap在mmap映射的data上直接运行正则，并用lineIndex把字节偏移
+// 换算成行号，行内容通过向前/向后查找最近的换行符截取得到
+func searchFileMmap(path string, data []byte, re *regexp.Regexp, maxChars, contextChars int) []Match {
+	if len(data) == 0 {
+		return nil
+	}
+
+	idx := newLineIndex(data)
+	var matches []Match
+
+	for _, hit := range re.FindAllIndex(data, -1) {
+		start, end := hit[0], hit[1]
+
+		lineStart := 0
+		if i := bytes.LastIndexByte(data[:start], '\n'); i != -1 {
+			lineStart = i + 1
+		}
+		lineEnd := len(data)
+		if i := bytes.IndexByte(data[end:], '\n'); i != -1 {
+			lineEnd = end + i
+		}
+
+		fullLine := string(data[lineStart:lineEnd])
+		localStart := start - lineStart
+		localEnd := end - lineStart
+
+		trimmedLine := extractMatchContext(fullLine, localStart, localEnd, maxChars, contextChars)
+		matches = append(matches, Match{
+			Path:       path,
+			LineNum:    idx.lineAt(start),
+			Text:       trimmedLine,
+			MatchText:  fullLine[localStart:localEnd],
+			ByteCol:    localStart,
+			RuneCol:    len([]rune(fullLine[:localStart])),
+			ByteOffset: int64(start),
+		})
+	}
+
+	return matches
+}
@@ -0,0 +1,11 @@
+// mmap_fallback.go
+//go:build !unix
+
+package main
+
+import "errors"
+
+// openMmap在非unix平台上没有实现，searchFile会据此退回bufio.Reader路径
+func openMmap(path string) ([]byte, func() error, error) {
+	return nil, nil, errors.New("当前平台不支持mmap扫描")
+}
@@ -0,0 +1,46 @@
+// mmap_unix.go
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// openMmap把path只读映射进内存，调用方必须调用返回的closer释放映射并关闭文件
+func openMmap(path string) ([]byte, func() error, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	size := info.Size()
+	if size == 0 {
+		f.Close()
+		return []byte{}, func() error { return nil }, nil
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	closer := func() error {
+		munmapErr := syscall.Munmap(data)
+		closeErr := f.Close()
+		if munmapErr != nil {
+			return munmapErr
+		}
+		return closeErr
+	}
+
+	return data, closer, nil
+}
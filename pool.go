@@ -0,0 +1,185 @@
+// pool.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sync"
+)
+
+// Match 表示一次匹配结果，用于在worker与打印协程之间传递
+type Match struct {
+	Path       string
+	LineNum    int
+	Text       string // 经过裁剪的上下文片段，供TextEmitter展示
+	MatchText  string // 命中的原始子串
+	ByteCol    int    // 匹配起始位置在行内的字节偏移
+	RuneCol    int    // 匹配起始位置在行内的rune偏移
+	ByteOffset int64  // 匹配起始位置相对文件开头的字节偏移
+	Binary     bool   // 该记录是否来自-binary=match-only的整文件匹配提示
+}
+
+// fileJob 是分发给worker的单个任务
+type fileJob struct {
+	index int
+	path  string
+}
+
+// fileResult 是worker处理完一个文件后的结果，index用于保持输出顺序
+type fileResult struct {
+	index    int
+	path     string
+	matches  []Match
+	ctxLines []ContextLine
+	err      error
+}
+
+// searchOptions 汇总了搜索过程中需要的只读参数
+type searchOptions struct {
+	re             *regexp.Regexp
+	showFilename   bool
+	showLineNum    bool
+	maxChars       int
+	contextChars   int
+	textOnly       bool
+	binaryMode     string
+	binaryExplicit bool // -binary是否由用户显式指定，显式指定时优先于-text-only生效
+
+	// contextMode开启时使用-A/-B/-C的整行上下文输出，
+	// 与maxChars/contextChars的片段模式互斥
+	contextMode bool
+	beforeLines int
+	afterLines  int
+
+	// emitter负责渲染非contextMode下的匹配结果（text/json/jsonl）
+	emitter Emitter
+}
+
+// runSearch 使用一个有界worker池并发搜索files，并通过单一打印协程
+// 按文件原始顺序输出结果，确保不同文件的匹配行不会交错。
+// ctx被传给每个worker和分发协程，调用方可以通过取消ctx中途停止剩余的搜索
+// （例如main()收到SIGINT时）；返回值是读取失败的文件数，供调用方决定退出码
+func runSearch(ctx context.Context, files []string, workers int, opts searchOptions) int {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan fileJob)
+	results := make(chan fileResult)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			searchWorker(ctx, jobs, results, opts)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	go func() {
+		defer close(jobs)
+		for i, path := range files {
+			select {
+			case jobs <- fileJob{index: i, path: path}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return printOrdered(files, results, opts)
+}
+
+// searchWorker 从jobs中取出文件路径，搜索后把结果发送到results
+func searchWorker(ctx context.Context, jobs <-chan fileJob, results chan<- fileResult, opts searchOptions) {
+	for job := range jobs {
+		var res fileResult
+		res.index = job.index
+		res.path = job.path
+
+		if opts.contextMode {
+			res.ctxLines, res.err = searchFileContext(job.path, opts.re, opts.beforeLines, opts.afterLines, opts.textOnly, opts.binaryMode, opts.binaryExplicit)
+		} else {
+			res.matches, res.err = searchFile(job.path, opts.re, opts.maxChars, opts.contextChars, opts.textOnly, opts.binaryMode, opts.binaryExplicit)
+		}
+
+		select {
+		case results <- res:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// printOrdered 是唯一的打印协程，缓存乱序到达的结果，按index顺序输出，
+// 从而保证不同文件的内容不会交错。返回遇到错误的文件数，用于main()汇总并
+// 决定退出码——单个文件的错误只是跳过该文件继续处理，但累计出现过错误时
+// 整个进程应以非零状态退出，而不是悄悄吞掉
+func printOrdered(files []string, results <-chan fileResult, opts searchOptions) int {
+	pending := make(map[int]fileResult)
+	expected := 0
+	errCount := 0
+
+	// prevPath/prevLine跟踪上一次输出的上下文行，用于判断组之间是否连续，
+	// 不连续时插入grep风格的"--"分隔符
+	prevPath := ""
+	prevLine := 0
+	first := true
+
+	flush := func(res fileResult) {
+		if res.err != nil {
+			errCount++
+			log.Printf("读取文件 %s 错误: %v", res.path, res.err)
+		}
+
+		if opts.contextMode {
+			for _, cl := range res.ctxLines {
+				if !first && !(res.path == prevPath && cl.LineNum == prevLine+1) {
+					fmt.Println("--")
+				}
+				first = false
+				printContextLine(res.path, cl, opts.showFilename, opts.showLineNum)
+				prevPath = res.path
+				prevLine = cl.LineNum
+			}
+			return
+		}
+
+		for _, m := range res.matches {
+			rec := MatchRecord{
+				Path:       m.Path,
+				Line:       m.LineNum,
+				Column:     ColumnOffset{Byte: m.ByteCol, Rune: m.RuneCol},
+				Match:      m.MatchText,
+				Context:    m.Text,
+				ByteOffset: m.ByteOffset,
+				Binary:     m.Binary,
+			}
+			if err := opts.emitter.Emit(rec); err != nil {
+				log.Printf("输出匹配结果失败: %v", err)
+			}
+		}
+	}
+
+	for res := range results {
+		pending[res.index] = res
+		for {
+			res, ok := pending[expected]
+			if !ok {
+				break
+			}
+			delete(pending, expected)
+			flush(res)
+			expected++
+		}
+	}
+
+	return errCount
+}
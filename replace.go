@@ -0,0 +1,111 @@
+// replace.go
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// replaceOptions汇总了-replace模式下需要的参数
+type replaceOptions struct {
+	replacement string // regexp.ReplaceAll语义的替换模板，支持$1、${name}
+	dryRun      bool   // 只打印unified diff预览，不修改文件
+	backupExt   string // 非空时，替换前把原文件备份为 原路径+该后缀
+}
+
+// runReplace对files中的每个文件执行正则替换，把wingrep变成一个sed -i/sd风格的
+// 原地重写工具。写入通过"写临时文件+os.Rename"实现，保证对外是原子的，
+// 并保留原文件的权限和修改时间
+func runReplace(files []string, re *regexp.Regexp, opts replaceOptions) {
+	for _, path := range files {
+		if err := replaceInFile(path, re, opts); err != nil {
+			log.Printf("处理文件 %s 失败: %v", path, err)
+		}
+	}
+}
+
+func replaceInFile(path string, re *regexp.Regexp, opts replaceOptions) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if looksBinary(original[:min(len(original), binaryProbeSize)]) {
+		return nil
+	}
+
+	updated := re.ReplaceAll(original, []byte(opts.replacement))
+	if string(updated) == string(original) {
+		return nil
+	}
+
+	if opts.dryRun {
+		diff := unifiedDiff(path, string(original), string(updated))
+		fmt.Print(diff)
+		return nil
+	}
+
+	if opts.backupExt != "" {
+		if err := os.WriteFile(path+opts.backupExt, original, info.Mode()); err != nil {
+			return fmt.Errorf("写入备份文件失败: %w", err)
+		}
+	}
+
+	if err := atomicWriteFile(path, updated, info); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s: 已替换\n", path)
+	return nil
+}
+
+// atomicWriteFile在path所在目录写一个临时文件，写完后用os.Rename原子替换原文件，
+// 避免进程中途失败导致原文件被截断或损坏；同时保留原文件的权限和修改时间
+func atomicWriteFile(path string, data []byte, info os.FileInfo) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, ".wingrep-tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Chmod(tmpPath, info.Mode()); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chtimes(tmpPath, info.ModTime(), info.ModTime()); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,107 @@
+// replace_test.go
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicWriteFileReplacesContentAndPreservesMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("old"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := atomicWriteFile(path, []byte("new"), info); err != nil {
+		t.Fatalf("atomicWriteFile失败: %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "new" {
+		t.Errorf("内容 = %q, want %q", got, "new")
+	}
+
+	newInfo, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newInfo.Mode() != info.Mode() {
+		t.Errorf("文件权限 = %v, want %v", newInfo.Mode(), info.Mode())
+	}
+}
+
+func TestAtomicWriteFileLeavesNoTempFileOnSuccess(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := atomicWriteFile(path, []byte("new"), info); err != nil {
+		t.Fatalf("atomicWriteFile失败: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "a.txt" {
+		t.Errorf("目录残留了非预期的文件: %v", entries)
+	}
+}
+
+func TestUnifiedDiffProducesHunk(t *testing.T) {
+	diff := unifiedDiff("a.txt", "foo\nbar\nbaz\n", "foo\nqux\nbaz\n")
+	if diff == "" {
+		t.Fatal("内容确实不同，unifiedDiff不应返回空串")
+	}
+	if !contains(diff, "-bar") || !contains(diff, "+qux") {
+		t.Errorf("diff输出缺少预期的增删行: %s", diff)
+	}
+}
+
+func TestUnifiedDiffSameContentIsEmpty(t *testing.T) {
+	if diff := unifiedDiff("a.txt", "same\n", "same\n"); diff != "" {
+		t.Errorf("内容相同时unifiedDiff应返回空串, got %q", diff)
+	}
+}
+
+func TestUnifiedDiffSkipsLineCountAboveCap(t *testing.T) {
+	big := make([]byte, 0)
+	for i := 0; i < diffLineCap+1; i++ {
+		big = append(big, []byte("line\n")...)
+	}
+	other := append([]byte{}, big...)
+	other[0] = 'L' // 保证内容不同，触发diff逻辑
+
+	diff := unifiedDiff("big.txt", string(big), string(other))
+	if diff == "" {
+		t.Fatal("内容不同时应返回提示，而不是空串")
+	}
+	if contains(diff, "@@") {
+		t.Errorf("超过diffLineCap行时不应再计算逐行diff，got %s", diff)
+	}
+}
+
+func contains(s, sub string) bool {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if s[i:i+len(sub)] == sub {
+			return true
+		}
+	}
+	return false
+}